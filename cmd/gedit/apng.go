@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"io"
+)
+
+var pngSignature = []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1a, '\n'}
+
+// pngChunk is a single length-prefixed chunk out of a PNG byte stream, with
+// the trailing CRC stripped (not checked) by parsePNGChunks.
+type pngChunk struct {
+	Type string
+	Data []byte
+}
+
+// parsePNGChunks splits a well-formed PNG byte stream into its chunks.
+func parsePNGChunks(data []byte) ([]pngChunk, error) {
+	if len(data) < 8 || !bytes.Equal(data[:8], pngSignature) {
+		return nil, fmt.Errorf("not a png stream")
+	}
+
+	var chunks []pngChunk
+	for i := 8; i < len(data); {
+		if i+8 > len(data) {
+			return nil, fmt.Errorf("truncated png chunk header")
+		}
+		length := binary.BigEndian.Uint32(data[i : i+4])
+		typ := string(data[i+4 : i+8])
+		start := i + 8
+		end := start + int(length)
+		if end+4 > len(data) {
+			return nil, fmt.Errorf("truncated %s chunk", typ)
+		}
+		chunks = append(chunks, pngChunk{Type: typ, Data: data[start:end]})
+		i = end + 4 // skip past the trailing CRC
+	}
+	return chunks, nil
+}
+
+// writeChunk writes a length-prefixed, CRC-terminated PNG chunk.
+func writeChunk(w io.Writer, typ string, data []byte) error {
+	var length [4]byte
+	binary.BigEndian.PutUint32(length[:], uint32(len(data)))
+	if _, err := w.Write(length[:]); err != nil {
+		return err
+	}
+
+	typeAndData := append([]byte(typ), data...)
+	if _, err := w.Write(typeAndData); err != nil {
+		return err
+	}
+
+	var crc [4]byte
+	binary.BigEndian.PutUint32(crc[:], crc32.ChecksumIEEE(typeAndData))
+	_, err := w.Write(crc[:])
+	return err
+}
+
+// apngEncoder is a pure-Go animated PNG writer. Rather than reimplementing
+// PNG's scanline filtering and zlib compression, it encodes every frame
+// individually with image/png and harvests the resulting IHDR/PLTE/tRNS/
+// IDAT chunks, repackaging the IDAT payloads as fdAT chunks per the APNG
+// spec (https://wiki.mozilla.org/APNG_Specification). All frames are
+// assumed to share frame 0's dimensions.
+type apngEncoder struct{}
+
+func (apngEncoder) Encode(w io.Writer, frames []image.Image, delays []int) error {
+	if len(frames) == 0 {
+		return fmt.Errorf("no frames to encode")
+	}
+
+	encoded := make([][]pngChunk, len(frames))
+	for i, frame := range frames {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, frame); err != nil {
+			return err
+		}
+		chunks, err := parsePNGChunks(buf.Bytes())
+		if err != nil {
+			return err
+		}
+		encoded[i] = chunks
+	}
+
+	if _, err := w.Write(pngSignature); err != nil {
+		return err
+	}
+
+	for _, chunk := range encoded[0] {
+		if chunk.Type == "IHDR" {
+			if err := writeChunk(w, chunk.Type, chunk.Data); err != nil {
+				return err
+			}
+		}
+	}
+
+	actl := make([]byte, 8)
+	binary.BigEndian.PutUint32(actl[0:4], uint32(len(frames)))
+	binary.BigEndian.PutUint32(actl[4:8], 0) // play indefinitely
+	if err := writeChunk(w, "acTL", actl); err != nil {
+		return err
+	}
+
+	for _, chunk := range encoded[0] {
+		if chunk.Type == "PLTE" || chunk.Type == "tRNS" {
+			if err := writeChunk(w, chunk.Type, chunk.Data); err != nil {
+				return err
+			}
+		}
+	}
+
+	var seq uint32
+	for i, chunks := range encoded {
+		delay := 0
+		if i < len(delays) {
+			delay = delays[i]
+		}
+		if err := writeChunk(w, "fcTL", frameControlData(seq, frames[i].Bounds(), delay)); err != nil {
+			return err
+		}
+		seq++
+
+		var idat []byte
+		for _, chunk := range chunks {
+			if chunk.Type == "IDAT" {
+				idat = append(idat, chunk.Data...)
+			}
+		}
+
+		if i == 0 {
+			if err := writeChunk(w, "IDAT", idat); err != nil {
+				return err
+			}
+			continue
+		}
+
+		fdat := make([]byte, 4+len(idat))
+		binary.BigEndian.PutUint32(fdat[:4], seq)
+		copy(fdat[4:], idat)
+		if err := writeChunk(w, "fdAT", fdat); err != nil {
+			return err
+		}
+		seq++
+	}
+
+	return writeChunk(w, "IEND", nil)
+}
+
+func (apngEncoder) NeedsCompositedFrames() bool { return true }
+
+// frameControlData builds an fcTL chunk's payload: sequence number, frame
+// dimensions/offset, delay (expressed as delayCentis/100 seconds), and the
+// dispose/blend ops (APNG_DISPOSE_OP_NONE, APNG_BLEND_OP_OVER).
+func frameControlData(seq uint32, bounds image.Rectangle, delayCentis int) []byte {
+	data := make([]byte, 26)
+	binary.BigEndian.PutUint32(data[0:4], seq)
+	binary.BigEndian.PutUint32(data[4:8], uint32(bounds.Dx()))
+	binary.BigEndian.PutUint32(data[8:12], uint32(bounds.Dy()))
+	binary.BigEndian.PutUint32(data[12:16], 0)
+	binary.BigEndian.PutUint32(data[16:20], 0)
+	binary.BigEndian.PutUint16(data[20:22], uint16(delayCentis))
+	binary.BigEndian.PutUint16(data[22:24], 100)
+	data[24] = 0 // dispose_op: none
+	data[25] = 1 // blend_op: over
+	return data
+}
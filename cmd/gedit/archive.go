@@ -0,0 +1,178 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"image"
+	"image/color/palette"
+	"image/draw"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// toPaletted converts a decoded image to *image.Paletted, the type required
+// by gif.GIF.Image. Frames that are already paletted (the common case for
+// round-tripped gifs) are returned as-is; anything else (JPEG, BMP, ...) is
+// quantized against the Plan9 palette via Floyd-Steinberg dithering.
+func toPaletted(img image.Image) *image.Paletted {
+	if p, ok := img.(*image.Paletted); ok {
+		return p
+	}
+
+	bounds := img.Bounds()
+	paletted := image.NewPaletted(bounds, palette.Plan9)
+	draw.FloydSteinberg.Draw(paletted, bounds, img, bounds.Min)
+	return paletted
+}
+
+// frameSource describes a single candidate frame discovered from a directory
+// or archive, along with a way to open its contents on demand.
+type frameSource struct {
+	Name string
+	Open func() (io.ReadCloser, error)
+}
+
+// collectFrameSources resolves path to a sorted list of frameSources. path
+// may be a directory, which is recursed via filepath.Walk, or an archive
+// (.zip, .tar, .tar.gz, .tar.bz2) which is read as a virtual filesystem.
+func collectFrameSources(path string) ([]frameSource, error) {
+	stat, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var sources []frameSource
+	if stat.IsDir() {
+		sources, err = collectFromDir(path)
+	} else {
+		sources, err = collectFromArchive(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(sources, func(i, j int) bool { return sources[i].Name < sources[j].Name })
+
+	return sources, nil
+}
+
+func collectFromDir(root string) ([]frameSource, error) {
+	var sources []frameSource
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		sources = append(sources, frameSource{
+			Name: path,
+			Open: func() (io.ReadCloser, error) {
+				// Frames unpacked with --cache-dir are symlinks into the
+				// cache; resolve them so repacking shares storage across gifs.
+				resolved, err := filepath.EvalSymlinks(path)
+				if err != nil {
+					return nil, err
+				}
+				return os.Open(resolved)
+			},
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sources, nil
+}
+
+// collectFromArchive dispatches by strings.ToLower(filepath.Ext(path)), with
+// a special case for the two-part ".tar.gz"/".tar.bz2" extensions.
+func collectFromArchive(path string) ([]frameSource, error) {
+	lower := strings.ToLower(path)
+	switch {
+	case strings.HasSuffix(lower, ".tar.gz"):
+		return collectFromTar(path, func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) })
+	case strings.HasSuffix(lower, ".tar.bz2"):
+		return collectFromTar(path, func(r io.Reader) (io.Reader, error) { return bzip2.NewReader(r), nil })
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".zip":
+		return collectFromZip(path)
+	case ".tar":
+		return collectFromTar(path, func(r io.Reader) (io.Reader, error) { return r, nil })
+	default:
+		return nil, fmt.Errorf("unsupported archive format: %s", filepath.Ext(path))
+	}
+}
+
+func collectFromZip(path string) ([]frameSource, error) {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	var sources []frameSource
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		f := f
+		sources = append(sources, frameSource{
+			Name: f.Name,
+			Open: func() (io.ReadCloser, error) { return f.Open() },
+		})
+	}
+	return sources, nil
+}
+
+// collectFromTar reads every regular file entry out of a tar stream,
+// optionally passed through a decompressor (gzip, bzip2, or a no-op for
+// plain tar). Since archive/tar only reads forward, each entry's bytes are
+// buffered so it can be opened lazily like the other sources.
+func collectFromTar(path string, decompress func(io.Reader) (io.Reader, error)) ([]frameSource, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader, err := decompress(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var sources []frameSource
+	tr := tar.NewReader(reader)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		data, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		name := header.Name
+		sources = append(sources, frameSource{
+			Name: name,
+			Open: func() (io.ReadCloser, error) { return ioutil.NopCloser(strings.NewReader(string(data))), nil },
+		})
+	}
+	return sources, nil
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"image"
+	"image/color"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeTestPNG(t *testing.T, path string) {
+	t.Helper()
+	img := image.NewPaletted(image.Rect(0, 0, 2, 2), []color.Color{color.White, color.Black})
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("creating %s: %v", path, err)
+	}
+	defer file.Close()
+	if err := png.Encode(file, img); err != nil {
+		t.Fatalf("encoding %s: %v", path, err)
+	}
+}
+
+func TestCollectFromDirSortsAndKeepsNonImages(t *testing.T) {
+	dir := t.TempDir()
+	writeTestPNG(t, filepath.Join(dir, "b.png"))
+	writeTestPNG(t, filepath.Join(dir, "a.png"))
+	if err := ioutil.WriteFile(filepath.Join(dir, "readme.txt"), []byte("not an image"), 0644); err != nil {
+		t.Fatalf("writing readme.txt: %v", err)
+	}
+
+	sources, err := collectFrameSources(dir)
+	if err != nil {
+		t.Fatalf("collectFrameSources: %v", err)
+	}
+
+	if len(sources) != 3 {
+		t.Fatalf("expected 3 sources (non-images are skipped later, not here), got %d", len(sources))
+	}
+	if filepath.Base(sources[0].Name) != "a.png" || filepath.Base(sources[1].Name) != "b.png" {
+		t.Fatalf("expected sources sorted by name, got %s, %s", sources[0].Name, sources[1].Name)
+	}
+}
+
+func TestCollectFromArchiveTarGz(t *testing.T) {
+	dir := t.TempDir()
+	archivePath := filepath.Join(dir, "frames.tar.gz")
+
+	var pngBuf bytes.Buffer
+	img := image.NewPaletted(image.Rect(0, 0, 2, 2), []color.Color{color.White, color.Black})
+	if err := png.Encode(&pngBuf, img); err != nil {
+		t.Fatalf("encoding fixture png: %v", err)
+	}
+
+	archiveFile, err := os.Create(archivePath)
+	if err != nil {
+		t.Fatalf("creating archive: %v", err)
+	}
+	gw := gzip.NewWriter(archiveFile)
+	tw := tar.NewWriter(gw)
+	if err := tw.WriteHeader(&tar.Header{Name: "frame_0.png", Size: int64(pngBuf.Len()), Mode: 0644}); err != nil {
+		t.Fatalf("writing tar header: %v", err)
+	}
+	if _, err := tw.Write(pngBuf.Bytes()); err != nil {
+		t.Fatalf("writing tar entry: %v", err)
+	}
+	tw.Close()
+	gw.Close()
+	archiveFile.Close()
+
+	sources, err := collectFrameSources(archivePath)
+	if err != nil {
+		t.Fatalf("collectFrameSources: %v", err)
+	}
+	if len(sources) != 1 {
+		t.Fatalf("expected 1 source, got %d", len(sources))
+	}
+
+	reader, err := sources[0].Open()
+	if err != nil {
+		t.Fatalf("opening tar entry: %v", err)
+	}
+	defer reader.Close()
+	if _, err := png.Decode(reader); err != nil {
+		t.Fatalf("decoding tar entry as png: %v", err)
+	}
+}
+
+func TestCollectFromArchiveZip(t *testing.T) {
+	dir := t.TempDir()
+	archivePath := filepath.Join(dir, "frames.zip")
+
+	var pngBuf bytes.Buffer
+	img := image.NewPaletted(image.Rect(0, 0, 2, 2), []color.Color{color.White, color.Black})
+	if err := png.Encode(&pngBuf, img); err != nil {
+		t.Fatalf("encoding fixture png: %v", err)
+	}
+
+	archiveFile, err := os.Create(archivePath)
+	if err != nil {
+		t.Fatalf("creating archive: %v", err)
+	}
+	zw := zip.NewWriter(archiveFile)
+	entry, err := zw.Create("frame_0.png")
+	if err != nil {
+		t.Fatalf("creating zip entry: %v", err)
+	}
+	if _, err := entry.Write(pngBuf.Bytes()); err != nil {
+		t.Fatalf("writing zip entry: %v", err)
+	}
+	zw.Close()
+	archiveFile.Close()
+
+	sources, err := collectFrameSources(archivePath)
+	if err != nil {
+		t.Fatalf("collectFrameSources: %v", err)
+	}
+	if len(sources) != 1 {
+		t.Fatalf("expected 1 source, got %d", len(sources))
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/urfave/cli"
+)
+
+var (
+	cacheDirFlagValue string
+	cacheDirFlag      = cli.StringFlag{
+		Name:        "cache-dir",
+		Destination: &cacheDirFlagValue,
+	}
+)
+
+// prepareCacheDir creates the 256 two-hex-digit shard subdirectories
+// (00-ff) up front, so individual frame writes never need to create a
+// directory on the hot path.
+func prepareCacheDir(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for i := 0; i < 256; i++ {
+		shard := filepath.Join(dir, fmt.Sprintf("%02x", i))
+		if err := os.MkdirAll(shard, 0755); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cacheFrame content-addresses data by its md5 sum and writes it under
+// <dir>/<first-2-hex>/<full-hex>.png if it isn't already cached, returning
+// the path it lives at either way. Identical frames unpacked from different
+// gifs (or the same gif more than once) land on the same file.
+func cacheFrame(dir string, data []byte) (string, error) {
+	sum := md5.Sum(data)
+	hexSum := hex.EncodeToString(sum[:])
+	path := filepath.Join(dir, hexSum[:2], hexSum+".png")
+
+	if _, err := os.Stat(path); err == nil {
+		return path, nil
+	} else if !os.IsNotExist(err) {
+		return "", err
+	}
+
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return "", err
+	}
+	return path, nil
+}
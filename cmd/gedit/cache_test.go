@@ -0,0 +1,42 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCacheFrameDedupsIdenticalData(t *testing.T) {
+	dir := t.TempDir()
+	if err := prepareCacheDir(dir); err != nil {
+		t.Fatalf("prepareCacheDir failed: %v", err)
+	}
+
+	data := []byte("same frame bytes")
+	first, err := cacheFrame(dir, data)
+	if err != nil {
+		t.Fatalf("cacheFrame failed: %v", err)
+	}
+	second, err := cacheFrame(dir, data)
+	if err != nil {
+		t.Fatalf("cacheFrame failed: %v", err)
+	}
+	if first != second {
+		t.Fatalf("expected identical data to land on the same path, got %q and %q", first, second)
+	}
+
+	other, err := cacheFrame(dir, []byte("different frame bytes"))
+	if err != nil {
+		t.Fatalf("cacheFrame failed: %v", err)
+	}
+	if other == first {
+		t.Fatalf("expected different data to land on a different path, both got %q", first)
+	}
+
+	if rel, err := filepath.Rel(dir, first); err != nil || filepath.Dir(rel) == "." {
+		t.Fatalf("expected %q to live under a two-hex-digit shard of %q", first, dir)
+	}
+	if _, err := os.Stat(first); err != nil {
+		t.Fatalf("expected cached file to exist: %v", err)
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"image"
+	"image/draw"
+	"image/gif"
+)
+
+// canvasDimensions returns the logical screen size frames should be
+// composited onto: the manifest's recorded width/height when available,
+// otherwise the bounding box of every frame's own rectangle.
+func canvasDimensions(m *manifest, frames []image.Image) (int, int) {
+	if m != nil && m.Width > 0 && m.Height > 0 {
+		return m.Width, m.Height
+	}
+
+	width, height := 0, 0
+	for _, frame := range frames {
+		bounds := frame.Bounds()
+		if bounds.Max.X > width {
+			width = bounds.Max.X
+		}
+		if bounds.Max.Y > height {
+			height = bounds.Max.Y
+		}
+	}
+	return width, height
+}
+
+// compositeFrames renders every frame onto a full-size canvas, honoring
+// each frame's disposal method the same way a gif decoder would. gif's
+// partial, offset frames are a space optimization that only the gif format
+// itself understands; formats without that concept (apng, mp4, a png
+// montage) need every frame as a full, self-contained image.
+func compositeFrames(frames []image.Image, disposal []byte, width, height int) []image.Image {
+	canvas := image.NewRGBA(image.Rect(0, 0, width, height))
+	composited := make([]image.Image, len(frames))
+
+	for i, frame := range frames {
+		d := byte(0)
+		if i < len(disposal) {
+			d = disposal[i]
+		}
+
+		var previous *image.RGBA
+		if d == gif.DisposalPrevious {
+			previous = cloneRGBA(canvas)
+		}
+
+		draw.Draw(canvas, frame.Bounds(), frame, frame.Bounds().Min, draw.Over)
+		composited[i] = cloneRGBA(canvas)
+
+		switch d {
+		case gif.DisposalBackground:
+			draw.Draw(canvas, frame.Bounds(), image.Transparent, image.Point{}, draw.Src)
+		case gif.DisposalPrevious:
+			canvas = previous
+		}
+	}
+
+	return composited
+}
+
+func cloneRGBA(src *image.RGBA) *image.RGBA {
+	dst := image.NewRGBA(src.Bounds())
+	draw.Draw(dst, dst.Bounds(), src, src.Bounds().Min, draw.Src)
+	return dst
+}
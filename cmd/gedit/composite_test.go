@@ -0,0 +1,76 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/gif"
+	"testing"
+)
+
+func solidPaletted(rect image.Rectangle, c color.Color) *image.Paletted {
+	p := image.NewPaletted(rect, color.Palette{color.RGBA{}, c})
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			p.SetColorIndex(x, y, 1)
+		}
+	}
+	return p
+}
+
+// TestCompositeFramesRestoresSubRectangleFrame reproduces the scenario from
+// the review: a 20x20 frame 0, and a 5x5 frame 1 offset at (10, 10) — the
+// common disposal-optimized shape a real gif encoder produces. The
+// composited frame 1 must be full-canvas-size with frame 0's content
+// showing through everywhere except the 5x5 patch.
+func TestCompositeFramesRestoresSubRectangleFrame(t *testing.T) {
+	red := color.RGBA{R: 255, A: 255}
+	blue := color.RGBA{B: 255, A: 255}
+
+	frame0 := solidPaletted(image.Rect(0, 0, 20, 20), red)
+	frame1 := solidPaletted(image.Rect(10, 10, 15, 15), blue)
+
+	frames := []image.Image{frame0, frame1}
+	disposal := []byte{gif.DisposalNone, gif.DisposalNone}
+
+	composited := compositeFrames(frames, disposal, 20, 20)
+	if len(composited) != 2 {
+		t.Fatalf("expected 2 composited frames, got %d", len(composited))
+	}
+
+	for i, frame := range composited {
+		b := frame.Bounds()
+		if b.Dx() != 20 || b.Dy() != 20 {
+			t.Fatalf("frame %d: expected a 20x20 canvas, got %dx%d", i, b.Dx(), b.Dy())
+		}
+	}
+
+	second := composited[1]
+	if r, g, b, a := second.At(0, 0).RGBA(); r>>8 != 255 || g>>8 != 0 || b>>8 != 0 || a>>8 != 255 {
+		t.Fatalf("frame 1 at (0,0): expected frame 0's red to show through, got rgba(%d,%d,%d,%d)", r>>8, g>>8, b>>8, a>>8)
+	}
+	if r, g, b, a := second.At(12, 12).RGBA(); r>>8 != 0 || g>>8 != 0 || b>>8 != 255 || a>>8 != 255 {
+		t.Fatalf("frame 1 at (12,12): expected the blue patch, got rgba(%d,%d,%d,%d)", r>>8, g>>8, b>>8, a>>8)
+	}
+}
+
+func TestCompositeFramesDisposalBackgroundClearsPatch(t *testing.T) {
+	red := color.RGBA{R: 255, A: 255}
+	blue := color.RGBA{B: 255, A: 255}
+
+	frame0 := solidPaletted(image.Rect(0, 0, 10, 10), red)
+	frame1 := solidPaletted(image.Rect(2, 2, 4, 4), blue)
+	frame2 := solidPaletted(image.Rect(0, 0, 0, 0), red) // degenerate no-op third frame
+
+	frames := []image.Image{frame0, frame1, frame2}
+	disposal := []byte{gif.DisposalNone, gif.DisposalBackground, gif.DisposalNone}
+
+	composited := compositeFrames(frames, disposal, 10, 10)
+
+	third := composited[2]
+	if r, _, _, a := third.At(2, 2).RGBA(); r>>8 != 0 || a>>8 != 0 {
+		t.Fatalf("frame 2 at (2,2): expected the background-disposed patch to be transparent, got r=%d a=%d", r>>8, a>>8)
+	}
+	if r, _, _, a := third.At(5, 5).RGBA(); r>>8 != 255 || a>>8 != 255 {
+		t.Fatalf("frame 2 at (5,5): expected frame 0's red to still show, got r=%d a=%d", r>>8, a>>8)
+	}
+}
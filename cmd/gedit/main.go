@@ -1,11 +1,12 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"image"
 	"image/gif"
-	"image/png"
+	_ "image/jpeg"
 	"io"
 	"log"
 	"net/http"
@@ -14,6 +15,8 @@ import (
 	"strings"
 	"time"
 
+	_ "golang.org/x/image/bmp"
+
 	"github.com/urfave/cli"
 )
 
@@ -73,13 +76,19 @@ func main() {
 			Name:        "unpack",
 			Action:      func(c *cli.Context) error { return unpack(c, logger) },
 			Description: "unpack a gif into its images",
-			Flags:       []cli.Flag{outputDirFlag},
+			Flags:       []cli.Flag{outputDirFlag, concurrencyFlag, cacheDirFlag},
 		},
 		cli.Command{
 			Name:        "pack",
 			Action:      func(c *cli.Context) error { return pack(c, logger) },
-			Description: "pack a set of images into a gif",
-			Flags:       []cli.Flag{outputFileFlag},
+			Description: "pack a set of images into a gif, apng, mp4, or png montage",
+			Flags:       []cli.Flag{outputFileFlag, concurrencyFlag, fpsFlag, delayFlag, loopCountFlag, montageColumnsFlag},
+		},
+		cli.Command{
+			Name:        "serve",
+			Action:      func(c *cli.Context) error { return serve(c, logger) },
+			Description: "serve a directory of unpacked frames or a gif over http",
+			Flags:       []cli.Flag{listenAddrFlag},
 		},
 	}
 
@@ -95,74 +104,99 @@ func pack(ctx *cli.Context, logger *log.Logger) error {
 	}
 	path := ctx.Args()[0]
 
-	if stat, err := os.Stat(path); err != nil {
+	stat, err := os.Stat(path)
+	if err != nil {
 		if os.IsNotExist(err) {
-			logger.Printf("Directory does not exist: '%s'.\n", path)
-			return err
+			logger.Printf("Path does not exist: '%s'.\n", path)
 		}
 		return err
-	} else if !stat.IsDir() {
-		logger.Printf("File '%s' is not a directory.\n", path)
-		return err
 	}
 
-	logger.Printf("Opening directory '%s'.\n", path)
-	dir, err := os.Open(path)
+	var m *manifest
+	if stat.IsDir() {
+		if m, err = readManifest(path); err != nil {
+			logger.Println("Failed reading manifest:", err.Error())
+			return err
+		}
+	}
+
+	logger.Printf("Discovering frames in '%s'.\n", path)
+	start := time.Now()
+	sources, err := collectFrameSources(path)
 	if err != nil {
-		logger.Println("Failed opening file:", err.Error())
+		logger.Println("Failed discovering frames:", err.Error())
 		return err
 	}
-	defer dir.Close()
+	logger.Printf("Discovered %d files in %dms.\n", len(sources), time.Since(start).Nanoseconds()/1000000)
 
-	logger.Println("Reading directory...")
-	infos, err := dir.Readdir(-1)
+	logger.Printf("Packing images with %d workers...\n", concurrencyFlagValue)
+	start = time.Now()
+	decoded, err := decodeFrames(context.Background(), sources, concurrencyFlagValue, logger)
 	if err != nil {
-		logger.Println("Failed reading file info from directory:", err.Error())
+		logger.Println("Failed decoding frames:", err.Error())
 		return err
 	}
 
-	start := time.Now()
-	filePaths := make([]string, 0)
-	for _, info := range infos {
-		if info.IsDir() {
-			logger.Printf("Skipping directory '%s'.\n", info.Name())
-			continue
+	byFile := map[string]frameManifest{}
+	if m != nil {
+		for _, fm := range m.Frames {
+			byFile[fm.File] = fm
 		}
-		logger.Printf("Discoverd file '%s'.\n", info.Name())
-		filePaths = append(filePaths, info.Name())
 	}
-	logger.Printf("Discovered %d files in %dms.\n", len(filePaths), time.Since(start).Nanoseconds()/1000000)
 
-	logger.Println("Packing images...")
-	start = time.Now()
-	newGif := &gif.GIF{}
-	for _, filePath := range filePaths {
-		file, err := os.Open(filepath.Join(path, filePath))
-		if err != nil {
-			logger.Println("Error opening file:", err.Error())
-			return err
+	delay := delayFlagValue
+	if delay < 0 && fpsFlagValue > 0 {
+		delay = int(100 / fpsFlagValue)
+	}
+
+	loopCount := loopCountFlagValue
+	if m != nil && loopCount == 0 {
+		loopCount = m.LoopCount
+	}
+
+	frames := make([]image.Image, len(decoded))
+	delays := make([]int, len(decoded))
+	disposal := make([]byte, len(decoded))
+	for i, d := range decoded {
+		frame := d.Frame
+		frameDelay := delay
+		if frameDelay < 0 {
+			frameDelay = 0
 		}
-		defer file.Close()
-		asGif, err := png.Decode(file)
-		if err != nil {
-			logger.Println("Failed decoding file as gif:", err.Error())
-			return err
+		if fm, ok := byFile[filepath.Base(d.Source.Name)]; ok {
+			if delay < 0 {
+				frameDelay = fm.Delay
+			}
+			disposal[i] = fm.Disposal
+			frame = translate(frame, fm.X, fm.Y)
 		}
-		newGif.Image = append(newGif.Image, asGif.(*image.Paletted))
-		newGif.Delay = append(newGif.Delay, 0)
+		frames[i] = frame
+		delays[i] = frameDelay
 	}
 	logger.Printf("Finished packing in %dms.\n", time.Since(start).Nanoseconds()/1000000)
 
-	logger.Println("Creating gif...")
 	outputFilePath := outputFileFlagValue
-	outputFile, err := os.OpenFile(outputFilePath, os.O_WRONLY|os.O_CREATE, 0600)
+	encoder, err := encoderFor(outputFilePath, loopCount, disposal, fpsFlagValue)
+	if err != nil {
+		logger.Println("Failed resolving output encoder:", err.Error())
+		return err
+	}
+
+	outputFrames := frames
+	if encoder.NeedsCompositedFrames() {
+		canvasWidth, canvasHeight := canvasDimensions(m, frames)
+		outputFrames = compositeFrames(frames, disposal, canvasWidth, canvasHeight)
+	}
+
+	logger.Printf("Creating '%s'...\n", outputFilePath)
+	outputFile, err := os.OpenFile(outputFilePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
 	if err != nil {
 		logger.Println("Failed opening output file:", err.Error())
 		return err
 	}
 	defer outputFile.Close()
-	if err := gif.EncodeAll(outputFile, newGif); err != nil {
-		logger.Println("Failed encoding gif:", err.Error())
+	if err := encoder.Encode(outputFile, outputFrames, delays); err != nil {
+		logger.Println("Failed encoding output:", err.Error())
 		return err
 	}
 
@@ -240,19 +274,61 @@ func unpack(ctx *cli.Context, logger *log.Logger) error {
 	}
 
 	start = time.Now()
-	logger.Println("Unpacking images.")
-	baseFilePath := filepath.Join(outputDir, strings.Replace(filepath.Base(path), filepath.Ext(path), "", -1))
-	for index, i := range g.Image {
-		fileName := fmt.Sprintf("%s_%d.png", baseFilePath, index)
-		logger.Printf("Creating file for image %d: '%s'\n", index, fileName)
-		file, err := os.Create(fileName)
-		if err != nil {
+	logger.Printf("Unpacking images with %d workers.\n", concurrencyFlagValue)
+	images := make([]image.Image, len(g.Image))
+	for i, paletted := range g.Image {
+		images[i] = paletted
+	}
+	encoded, err := encodeFrames(context.Background(), images, concurrencyFlagValue)
+	if err != nil {
+		logger.Println("Failed encoding frames:", err.Error())
+		return err
+	}
+
+	cacheEnabled := cacheDirFlagValue != ""
+	if cacheEnabled {
+		logger.Printf("Preparing cache directory '%s'.\n", cacheDirFlagValue)
+		if err := prepareCacheDir(cacheDirFlagValue); err != nil {
+			logger.Println("Failed preparing cache directory:", err.Error())
 			return err
 		}
-		if err := png.Encode(file, i); err != nil {
+	}
+
+	baseName := strings.Replace(filepath.Base(path), filepath.Ext(path), "", -1)
+	fileNames := make([]string, len(encoded))
+	for index, data := range encoded {
+		fileName := fmt.Sprintf("%s_%d.png", baseName, index)
+		fileNames[index] = fileName
+		filePath := filepath.Join(outputDir, fileName)
+		logger.Printf("Creating file for image %d: '%s'\n", index, filePath)
+
+		if cacheEnabled {
+			cachePath, err := cacheFrame(cacheDirFlagValue, data)
+			if err != nil {
+				logger.Println("Failed caching frame:", err.Error())
+				return err
+			}
+			target := cachePath
+			if rel, err := filepath.Rel(outputDir, cachePath); err == nil {
+				target = rel
+			}
+			os.Remove(filePath)
+			if err := os.Symlink(target, filePath); err != nil {
+				logger.Println("Failed linking cached frame:", err.Error())
+				return err
+			}
+			continue
+		}
+
+		if err := os.WriteFile(filePath, data, 0644); err != nil {
 			logger.Println("Failed writing file:", err.Error())
 		}
-		file.Close()
+	}
+
+	logger.Println("Writing manifest.json...")
+	if err := writeManifest(outputDir, fileNames, g); err != nil {
+		logger.Println("Failed writing manifest:", err.Error())
+		return err
 	}
 	logger.Printf("Finished unpacking in %dms.\n", time.Since(start).Nanoseconds()/1000000)
 
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/gif"
+	"os"
+	"path/filepath"
+
+	"github.com/urfave/cli"
+)
+
+const manifestFileName = "manifest.json"
+
+var (
+	fpsFlagValue float64
+	fpsFlag      = cli.Float64Flag{
+		Name:        "fps",
+		Destination: &fpsFlagValue,
+	}
+	delayFlagValue int
+	delayFlag      = cli.IntFlag{
+		Name:        "delay",
+		Value:       -1,
+		Destination: &delayFlagValue,
+	}
+	loopCountFlagValue int
+	loopCountFlag      = cli.IntFlag{
+		Name:        "loop-count",
+		Destination: &loopCountFlagValue,
+	}
+)
+
+// frameManifest records everything needed to reconstruct a single gif
+// frame's timing and placement once it has been split out into a PNG.
+type frameManifest struct {
+	Index            int    `json:"index"`
+	File             string `json:"file"`
+	Delay            int    `json:"delay"` // 1/100s, per the gif spec
+	Disposal         byte   `json:"disposal"`
+	X                int    `json:"x"`
+	Y                int    `json:"y"`
+	Width            int    `json:"width"`
+	Height           int    `json:"height"`
+	TransparentIndex int    `json:"transparent_index"` // -1 if the frame has no transparent entry
+}
+
+// manifest is the sidecar manifest.json written alongside unpacked frames
+// so that pack can round-trip a gif's animation timing exactly. Width and
+// Height are the logical screen size (gif.GIF.Config), which individual
+// frames may be smaller than or offset within.
+type manifest struct {
+	LoopCount int             `json:"loop_count"`
+	Width     int             `json:"width"`
+	Height    int             `json:"height"`
+	Frames    []frameManifest `json:"frames"`
+}
+
+// writeManifest records g's per-frame timing/placement next to its already
+// unpacked frames, so pack can reconstruct it losslessly.
+func writeManifest(dir string, fileNames []string, g *gif.GIF) error {
+	m := manifest{LoopCount: g.LoopCount, Width: g.Config.Width, Height: g.Config.Height}
+	for i, paletted := range g.Image {
+		bounds := paletted.Bounds()
+		delay := 0
+		if i < len(g.Delay) {
+			delay = g.Delay[i]
+		}
+		disposal := byte(0)
+		if i < len(g.Disposal) {
+			disposal = g.Disposal[i]
+		}
+		m.Frames = append(m.Frames, frameManifest{
+			Index:            i,
+			File:             fileNames[i],
+			Delay:            delay,
+			Disposal:         disposal,
+			X:                bounds.Min.X,
+			Y:                bounds.Min.Y,
+			Width:            bounds.Dx(),
+			Height:           bounds.Dy(),
+			TransparentIndex: transparentIndex(paletted.Palette),
+		})
+	}
+
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, manifestFileName), data, 0644)
+}
+
+// readManifest loads the sidecar manifest.json from dir, if one exists. A
+// missing manifest is not an error; callers fall back to default timing.
+func readManifest(dir string) (*manifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, manifestFileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var m manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// transparentIndex returns the palette index of the first fully transparent
+// color, or -1 if the palette has none.
+func transparentIndex(p color.Palette) int {
+	for i, c := range p {
+		_, _, _, a := c.RGBA()
+		if a == 0 {
+			return i
+		}
+	}
+	return -1
+}
+
+// translate returns p shifted so its bounds originate at (x, y), reusing
+// the same pixel buffer. Used to restore a frame's offset within the gif's
+// logical screen after it was written out as a standalone, zero-origin PNG.
+func translate(p *image.Paletted, x, y int) *image.Paletted {
+	size := p.Rect.Size()
+	return &image.Paletted{
+		Pix:     p.Pix,
+		Stride:  p.Stride,
+		Rect:    image.Rect(x, y, x+size.X, y+size.Y),
+		Palette: p.Palette,
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/gif"
+	"testing"
+)
+
+func TestWriteReadManifestRoundTrips(t *testing.T) {
+	dir := t.TempDir()
+
+	palette := color.Palette{color.RGBA{}, color.RGBA{R: 255, A: 255}}
+	frame0 := image.NewPaletted(image.Rect(0, 0, 10, 10), palette)
+	frame1 := image.NewPaletted(image.Rect(2, 3, 7, 8), palette)
+
+	g := &gif.GIF{
+		Image:    []*image.Paletted{frame0, frame1},
+		Delay:    []int{50, 30},
+		Disposal: []byte{gif.DisposalNone, gif.DisposalBackground},
+		Config:   image.Config{Width: 10, Height: 10},
+	}
+	g.LoopCount = -1
+
+	fileNames := []string{"in_0.png", "in_1.png"}
+	if err := writeManifest(dir, fileNames, g); err != nil {
+		t.Fatalf("writeManifest failed: %v", err)
+	}
+
+	m, err := readManifest(dir)
+	if err != nil {
+		t.Fatalf("readManifest failed: %v", err)
+	}
+	if m == nil {
+		t.Fatal("expected a manifest, got nil")
+	}
+	if m.LoopCount != -1 || m.Width != 10 || m.Height != 10 {
+		t.Fatalf("unexpected manifest header: %+v", m)
+	}
+	if len(m.Frames) != 2 {
+		t.Fatalf("expected 2 frames, got %d", len(m.Frames))
+	}
+
+	second := m.Frames[1]
+	if second.File != "in_1.png" || second.Delay != 30 || second.Disposal != gif.DisposalBackground {
+		t.Fatalf("unexpected second frame: %+v", second)
+	}
+	if second.X != 2 || second.Y != 3 || second.Width != 5 || second.Height != 5 {
+		t.Fatalf("unexpected second frame placement: %+v", second)
+	}
+}
+
+func TestReadManifestMissingIsNotAnError(t *testing.T) {
+	m, err := readManifest(t.TempDir())
+	if err != nil {
+		t.Fatalf("expected no error for a missing manifest, got %v", err)
+	}
+	if m != nil {
+		t.Fatalf("expected a nil manifest, got %+v", m)
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// mp4Encoder shells out to ffmpeg, if present on $PATH, to mux frames into
+// an mp4. Frames must already be full, uniform-size images (see
+// compositeFrames), since ffmpeg's image2 demuxer requires a fixed
+// resolution across the sequence; they're written to a temporary directory
+// as numbered PNGs since that demuxer needs real files to read from.
+type mp4Encoder struct {
+	FPS float64
+}
+
+func (e mp4Encoder) Encode(w io.Writer, frames []image.Image, delays []int) error {
+	ffmpegPath, err := exec.LookPath("ffmpeg")
+	if err != nil {
+		return fmt.Errorf("mp4 output requires ffmpeg on $PATH: %w", err)
+	}
+
+	tmpDir, err := os.MkdirTemp("", "gedit-mp4-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	for i, frame := range frames {
+		file, err := os.Create(filepath.Join(tmpDir, fmt.Sprintf("frame_%06d.png", i)))
+		if err != nil {
+			return err
+		}
+		err = png.Encode(file, frame)
+		file.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	fps := e.FPS
+	if fps <= 0 {
+		fps = 10
+	}
+
+	outputPath := filepath.Join(tmpDir, "out.mp4")
+	cmd := exec.Command(ffmpegPath,
+		"-y",
+		"-framerate", fmt.Sprintf("%f", fps),
+		"-i", filepath.Join(tmpDir, "frame_%06d.png"),
+		"-pix_fmt", "yuv420p",
+		outputPath,
+	)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("ffmpeg failed: %w: %s", err, out)
+	}
+
+	output, err := os.Open(outputPath)
+	if err != nil {
+		return err
+	}
+	defer output.Close()
+
+	_, err = io.Copy(w, output)
+	return err
+}
+
+func (e mp4Encoder) NeedsCompositedFrames() bool { return true }
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/png"
+	"log"
+	"runtime"
+	"sync"
+
+	"github.com/urfave/cli"
+)
+
+var (
+	concurrencyFlagValue int
+	concurrencyFlag      = cli.IntFlag{
+		Name:        "concurrency",
+		Value:       runtime.NumCPU(),
+		Destination: &concurrencyFlagValue,
+	}
+)
+
+// decodeJob and decodeResult carry a frameSource through the pack pipeline's
+// workers. seq preserves the source's original position so the collector
+// can assemble frames back in order despite out-of-order completion.
+type decodeJob struct {
+	seq    int
+	source frameSource
+}
+
+type decodeResult struct {
+	seq   int
+	frame *image.Paletted
+	err   error
+}
+
+// decodedFrame pairs a decoded frame with the source it came from, so
+// callers can still look up per-source metadata (e.g. a manifest entry)
+// after decoding has reordered/compacted the work.
+type decodedFrame struct {
+	Source frameSource
+	Frame  *image.Paletted
+}
+
+// decodeFrames runs sources through a source -> decode -> collect pipeline:
+// a source goroutine feeds jobs to n worker goroutines, which decode frames
+// in parallel, while this goroutine collects results and reassembles them
+// in their original order. The first error opening a source cancels ctx
+// and is returned; sources that fail to decode as images (non-image files)
+// are logged and omitted.
+func decodeFrames(ctx context.Context, sources []frameSource, n int, logger *log.Logger) ([]decodedFrame, error) {
+	if n < 1 {
+		return nil, fmt.Errorf("concurrency must be positive, got %d", n)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	jobs := make(chan decodeJob)
+	results := make(chan decodeResult)
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				file, err := job.source.Open()
+				if err != nil {
+					results <- decodeResult{seq: job.seq, err: err}
+					continue
+				}
+				img, _, err := image.Decode(file)
+				file.Close()
+				if err != nil {
+					logger.Printf("Skipping '%s', not a decodable image: %s\n", job.source.Name, err.Error())
+					results <- decodeResult{seq: job.seq}
+					continue
+				}
+				results <- decodeResult{seq: job.seq, frame: toPaletted(img)}
+			}
+		}()
+	}
+
+	go func() {
+		defer close(jobs)
+		for i, source := range sources {
+			select {
+			case <-ctx.Done():
+				return
+			case jobs <- decodeJob{seq: i, source: source}:
+			}
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	frames := make([]*image.Paletted, len(sources))
+	var firstErr error
+	for res := range results {
+		if res.err != nil {
+			if firstErr == nil {
+				firstErr = res.err
+				cancel()
+			}
+			continue
+		}
+		frames[res.seq] = res.frame
+	}
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	var decoded []decodedFrame
+	for i, frame := range frames {
+		if frame != nil {
+			decoded = append(decoded, decodedFrame{Source: sources[i], Frame: frame})
+		}
+	}
+	return decoded, nil
+}
+
+// encodeJob and encodeResult carry a decoded gif frame through the unpack
+// pipeline's workers, again keyed by seq to preserve ordering.
+type encodeJob struct {
+	seq int
+	img image.Image
+}
+
+type encodeResult struct {
+	seq  int
+	data []byte
+	err  error
+}
+
+// encodeFrames runs images through a source -> encode -> collect pipeline
+// identical in shape to decodeFrames, except workers encode PNGs instead of
+// decoding them. The returned slice is ordered to match images.
+func encodeFrames(ctx context.Context, images []image.Image, n int) ([][]byte, error) {
+	if n < 1 {
+		return nil, fmt.Errorf("concurrency must be positive, got %d", n)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	jobs := make(chan encodeJob)
+	results := make(chan encodeResult)
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				var buf bytes.Buffer
+				if err := png.Encode(&buf, job.img); err != nil {
+					results <- encodeResult{seq: job.seq, err: err}
+					continue
+				}
+				results <- encodeResult{seq: job.seq, data: buf.Bytes()}
+			}
+		}()
+	}
+
+	go func() {
+		defer close(jobs)
+		for i, img := range images {
+			select {
+			case <-ctx.Done():
+				return
+			case jobs <- encodeJob{seq: i, img: img}:
+			}
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	encoded := make([][]byte, len(images))
+	var firstErr error
+	for res := range results {
+		if res.err != nil {
+			if firstErr == nil {
+				firstErr = res.err
+				cancel()
+			}
+			continue
+		}
+		encoded[res.seq] = res.data
+	}
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return encoded, nil
+}
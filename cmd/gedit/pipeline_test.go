@@ -0,0 +1,25 @@
+package main
+
+import (
+	"context"
+	"image"
+	"io/ioutil"
+	"log"
+	"testing"
+)
+
+func TestDecodeFramesRejectsNonPositiveConcurrency(t *testing.T) {
+	logger := log.New(ioutil.Discard, "", 0)
+	if _, err := decodeFrames(context.Background(), nil, 0, logger); err == nil {
+		t.Fatal("expected an error for concurrency 0, got nil")
+	}
+	if _, err := decodeFrames(context.Background(), nil, -1, logger); err == nil {
+		t.Fatal("expected an error for negative concurrency, got nil")
+	}
+}
+
+func TestEncodeFramesRejectsNonPositiveConcurrency(t *testing.T) {
+	if _, err := encodeFrames(context.Background(), []image.Image{}, 0); err == nil {
+		t.Fatal("expected an error for concurrency 0, got nil")
+	}
+}
@@ -0,0 +1,311 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"image/gif"
+	"image/png"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/urfave/cli"
+)
+
+var (
+	listenAddrFlagValue string
+	listenAddrFlag      = cli.StringFlag{
+		Name:        "listen",
+		Value:       ":8080",
+		Destination: &listenAddrFlagValue,
+	}
+)
+
+// dirListTemplate renders a directory listing of served frames, modeled on
+// the unexported dirList template used by net/http.FileServer.
+var dirListTemplate = template.Must(template.New("dirlist").Parse(`<!doctype html>
+<meta name="viewport" content="width=device-width">
+<pre>
+{{range .}}<a href="frame/{{.}}">{{.}}</a>
+{{end}}<a href="gif">gif</a>
+</pre>
+`))
+
+// frameProvider serves up frames for the "serve" command, backed either by
+// a local directory of already-unpacked PNGs or a gif (local or remote)
+// that is decoded lazily on first request.
+type frameProvider interface {
+	// List returns the display names of the available frames, in order.
+	List() ([]string, error)
+	// Frame returns the encoded PNG bytes for the frame with the given
+	// display name, one of the names List returns.
+	Frame(name string) ([]byte, error)
+	// Gif returns the frames assembled into a single gif.
+	Gif() (*gif.GIF, error)
+}
+
+// dirFrameProvider serves frames from a directory of PNG files on disk.
+type dirFrameProvider struct {
+	path string
+}
+
+func (d *dirFrameProvider) names() ([]string, error) {
+	dir, err := os.Open(d.path)
+	if err != nil {
+		return nil, err
+	}
+	defer dir.Close()
+
+	infos, err := dir.Readdir(-1)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, info := range infos {
+		if info.IsDir() || strings.ToLower(filepath.Ext(info.Name())) != ".png" {
+			continue
+		}
+		names = append(names, info.Name())
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (d *dirFrameProvider) List() ([]string, error) {
+	return d.names()
+}
+
+func (d *dirFrameProvider) Frame(name string) ([]byte, error) {
+	names, err := d.names()
+	if err != nil {
+		return nil, err
+	}
+	for _, candidate := range names {
+		if candidate == name {
+			return os.ReadFile(filepath.Join(d.path, name))
+		}
+	}
+	return nil, fmt.Errorf("frame %q not found", name)
+}
+
+// Gif reassembles the served directory's frames into a single gif,
+// restoring each frame's delay, disposal, and offset from the sidecar
+// manifest.json the same way pack does in main.go. A missing manifest
+// falls back to zero delay/disposal and zero-origin frames.
+func (d *dirFrameProvider) Gif() (*gif.GIF, error) {
+	names, err := d.names()
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := readManifest(d.path)
+	if err != nil {
+		return nil, err
+	}
+	byFile := map[string]frameManifest{}
+	if m != nil {
+		for _, fm := range m.Frames {
+			byFile[fm.File] = fm
+		}
+	}
+
+	g := &gif.GIF{}
+	if m != nil {
+		g.LoopCount = m.LoopCount
+		g.Config.Width = m.Width
+		g.Config.Height = m.Height
+	}
+	for _, name := range names {
+		file, err := os.Open(filepath.Join(d.path, name))
+		if err != nil {
+			return nil, err
+		}
+		img, err := png.Decode(file)
+		file.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		paletted := toPaletted(img)
+		delay := 0
+		disposal := byte(0)
+		if fm, ok := byFile[name]; ok {
+			delay = fm.Delay
+			disposal = fm.Disposal
+			paletted = translate(paletted, fm.X, fm.Y)
+		}
+		g.Image = append(g.Image, paletted)
+		g.Delay = append(g.Delay, delay)
+		g.Disposal = append(g.Disposal, disposal)
+	}
+	return g, nil
+}
+
+// gifFrameProvider serves frames out of a gif that is opened via a
+// FileReader (local or remote) and decoded once, lazily, on first request.
+type gifFrameProvider struct {
+	path   string
+	reader FileReader
+
+	mu     sync.Mutex
+	loaded bool
+	g      *gif.GIF
+}
+
+func newGifFrameProvider(path string) *gifFrameProvider {
+	var reader FileReader
+	if strings.HasPrefix(path, "http") {
+		reader = &URLFileReader{http.Client{Timeout: time.Second * 30}}
+	} else {
+		reader = &LocalFileReader{}
+	}
+	return &gifFrameProvider{path: path, reader: reader}
+}
+
+func (p *gifFrameProvider) ensureLoaded() (*gif.GIF, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.loaded {
+		return p.g, nil
+	}
+
+	rc, err := p.reader.Open(p.path)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	g, err := gif.DecodeAll(rc)
+	if err != nil {
+		return nil, err
+	}
+	p.g = g
+	p.loaded = true
+	return g, nil
+}
+
+func (p *gifFrameProvider) List() ([]string, error) {
+	g, err := p.ensureLoaded()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(g.Image))
+	for i := range g.Image {
+		names[i] = fmt.Sprintf("%d.png", i)
+	}
+	return names, nil
+}
+
+func (p *gifFrameProvider) Frame(name string) ([]byte, error) {
+	g, err := p.ensureLoaded()
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSuffix(name, ".png"))
+	if err != nil || n < 0 || n >= len(g.Image) {
+		return nil, fmt.Errorf("frame %q not found", name)
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, g.Image[n]); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (p *gifFrameProvider) Gif() (*gif.GIF, error) {
+	return p.ensureLoaded()
+}
+
+// newServeMux wires provider up to the "serve" command's routes, split out
+// from serve itself so it can be exercised directly in tests against an
+// httptest.Server.
+func newServeMux(provider frameProvider, logger *log.Logger) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		names, err := provider.List()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := dirListTemplate.Execute(w, names); err != nil {
+			logger.Println("Failed rendering directory listing:", err.Error())
+		}
+	})
+	mux.HandleFunc("/frame/", func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, "/frame/")
+		data, err := provider.Frame(name)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(data)
+	})
+	mux.HandleFunc("/gif", func(w http.ResponseWriter, r *http.Request) {
+		g, err := provider.Gif()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "image/gif")
+		if err := gif.EncodeAll(w, g); err != nil {
+			logger.Println("Failed streaming gif:", err.Error())
+		}
+	})
+	return mux
+}
+
+func serve(ctx *cli.Context, logger *log.Logger) error {
+	if ctx.NArg() < 1 {
+		return errors.New("expected a directory or gif path/url to be first argument")
+	}
+	path := ctx.Args()[0]
+
+	var provider frameProvider
+	if stat, err := os.Stat(path); err == nil && stat.IsDir() {
+		logger.Printf("Serving unpacked frames from directory '%s'.\n", path)
+		provider = &dirFrameProvider{path: path}
+	} else {
+		logger.Printf("Serving frames from gif '%s' (unpacked lazily).\n", path)
+		provider = newGifFrameProvider(path)
+	}
+
+	server := &http.Server{Addr: listenAddrFlagValue, Handler: newServeMux(provider, logger)}
+
+	shutdown := make(chan os.Signal, 1)
+	signal.Notify(shutdown, os.Interrupt)
+	go func() {
+		<-shutdown
+		logger.Println("Received interrupt, shutting down...")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			logger.Println("Error during shutdown:", err.Error())
+		}
+	}()
+
+	logger.Printf("Listening on '%s'.\n", listenAddrFlagValue)
+	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		return err
+	}
+
+	logger.Println("Server stopped.")
+	return nil
+}
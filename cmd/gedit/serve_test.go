@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeTestPNGFile(t *testing.T, path string, rect image.Rectangle, c color.Color) {
+	t.Helper()
+	palette := color.Palette{color.RGBA{}, c}
+	img := image.NewPaletted(rect, palette)
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			img.SetColorIndex(x, y, 1)
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("png.Encode failed: %v", err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatalf("writing %s failed: %v", path, err)
+	}
+}
+
+// TestServeDirectoryListingLinksResolve reproduces the review's scenario: a
+// directory of frames named the way unpack names them (in_0.png, not a bare
+// index), served and then fetched through the real index-page links.
+func TestServeDirectoryListingLinksResolve(t *testing.T) {
+	dir := t.TempDir()
+	writeTestPNGFile(t, filepath.Join(dir, "in_0.png"), image.Rect(0, 0, 4, 4), color.RGBA{R: 255, A: 255})
+	writeTestPNGFile(t, filepath.Join(dir, "in_1.png"), image.Rect(0, 0, 4, 4), color.RGBA{B: 255, A: 255})
+	if err := writeManifest(dir, []string{"in_0.png", "in_1.png"}, &gif.GIF{
+		Image:    []*image.Paletted{image.NewPaletted(image.Rect(0, 0, 4, 4), nil), image.NewPaletted(image.Rect(0, 0, 4, 4), nil)},
+		Delay:    []int{50, 30},
+		Disposal: []byte{gif.DisposalNone, gif.DisposalBackground},
+		Config:   image.Config{Width: 4, Height: 4},
+	}); err != nil {
+		t.Fatalf("writeManifest failed: %v", err)
+	}
+
+	provider := &dirFrameProvider{path: dir}
+	mux := newServeMux(provider, log.New(ioutil.Discard, "", 0))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	indexResp, err := http.Get(server.URL + "/")
+	if err != nil {
+		t.Fatalf("GET / failed: %v", err)
+	}
+	defer indexResp.Body.Close()
+	var index bytes.Buffer
+	index.ReadFrom(indexResp.Body)
+	if !strings.Contains(index.String(), `href="frame/in_0.png"`) {
+		t.Fatalf("expected index page to link frame/in_0.png, got:\n%s", index.String())
+	}
+
+	frameResp, err := http.Get(server.URL + "/frame/in_0.png")
+	if err != nil {
+		t.Fatalf("GET /frame/in_0.png failed: %v", err)
+	}
+	defer frameResp.Body.Close()
+	if frameResp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 following the real index link, got %d", frameResp.StatusCode)
+	}
+
+	gifResp, err := http.Get(server.URL + "/gif")
+	if err != nil {
+		t.Fatalf("GET /gif failed: %v", err)
+	}
+	defer gifResp.Body.Close()
+	g, err := gif.DecodeAll(gifResp.Body)
+	if err != nil {
+		t.Fatalf("decoding /gif response failed: %v", err)
+	}
+	if len(g.Delay) != 2 || g.Delay[0] != 50 || g.Delay[1] != 30 {
+		t.Fatalf("expected manifest delays [50 30] to survive the round trip, got %v", g.Delay)
+	}
+	if len(g.Disposal) != 2 || g.Disposal[0] != gif.DisposalNone || g.Disposal[1] != gif.DisposalBackground {
+		t.Fatalf("expected manifest disposal to survive the round trip, got %v", g.Disposal)
+	}
+}
+
+func TestServeUnknownFrame404s(t *testing.T) {
+	dir := t.TempDir()
+	writeTestPNGFile(t, filepath.Join(dir, "in_0.png"), image.Rect(0, 0, 2, 2), color.RGBA{R: 255, A: 255})
+
+	provider := &dirFrameProvider{path: dir}
+	server := httptest.NewServer(newServeMux(provider, log.New(ioutil.Discard, "", 0)))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/frame/" + url.PathEscape("missing.png"))
+	if err != nil {
+		t.Fatalf("GET failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown frame, got %d", resp.StatusCode)
+	}
+}